@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+)
+
+// auditLogger emits structured JSON audit events. We log a SHA-256 hash of
+// the email rather than the address itself so the audit trail doesn't
+// become a second copy of the subscriber list.
+var auditLogger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// hashEmail returns a hex-encoded SHA-256 hash of email for audit logging.
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return hex.EncodeToString(sum[:])
+}
+
+// logAudit emits one structured audit event.
+func logAudit(event, emailHash, remoteIP, userAgent, status string, latencyMs int64) {
+	auditLogger.Info("audit",
+		"event", event,
+		"email_hash", emailHash,
+		"remote_ip", remoteIP,
+		"user_agent", userAgent,
+		"status", status,
+		"latency_ms", latencyMs,
+	)
+}
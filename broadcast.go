@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// BroadcastRequest is the expected JSON body for POST /coming-soon/broadcast.
+type BroadcastRequest struct {
+	Subject      string            `json:"subject" binding:"required"`
+	TextBody     string            `json:"text_body" binding:"required"`
+	HTMLBody     string            `json:"html_body"`
+	TemplateVars map[string]string `json:"template_vars"`
+}
+
+// broadcastRecipient tracks the send outcome for a single subscriber so a
+// crashed job can be resumed without re-mailing everyone.
+type broadcastRecipient struct {
+	Email  string `json:"email"`
+	Status string `json:"status"` // "pending", "sent" or "failed"
+}
+
+// broadcastJob tracks progress of one POST /coming-soon/broadcast call. It
+// is persisted to BROADCAST_LOG_DIR as a whole, including Req, so a
+// restarted process can reload it and retry failed recipients.
+type broadcastJob struct {
+	ID         string                `json:"id"`
+	Subject    string                `json:"subject"`
+	CreatedAt  time.Time             `json:"created_at"`
+	Total      int                   `json:"total"`
+	Sent       int                   `json:"sent"`
+	Failed     int                   `json:"failed"`
+	Remaining  int                   `json:"remaining"`
+	Recipients []*broadcastRecipient `json:"recipients"`
+	Req        BroadcastRequest      `json:"request"`
+	mutex      sync.Mutex            `json:"-"`
+}
+
+var (
+	broadcastJobs      = make(map[string]*broadcastJob)
+	broadcastJobsMutex sync.Mutex
+)
+
+// spawnBroadcastJob starts job's send in the background. It is a variable
+// so tests can swap in a synchronous implementation and observe the job's
+// final state without racing the worker pool.
+var spawnBroadcastJob = func(job *broadcastJob) {
+	go runBroadcastJob(job)
+}
+
+// broadcastWorkers and broadcastRatePerSec are configurable via
+// BROADCAST_WORKERS and BROADCAST_RATE_PER_SEC so deployments can stay
+// under their mail provider's send-rate quota.
+var (
+	broadcastWorkers    = envInt("BROADCAST_WORKERS", 5)
+	broadcastRatePerSec = envInt("BROADCAST_RATE_PER_SEC", 5)
+	broadcastLogDir     = envDefault("BROADCAST_LOG_DIR", "broadcast_jobs")
+)
+
+func envInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func envDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// broadcastHandler accepts a newsletter request, persists a job record, and
+// dispatches sends in the background, returning immediately with a job ID.
+func broadcastHandler(c *gin.Context) {
+	var req BroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. 'subject' and 'text_body' are required."})
+		return
+	}
+
+	records, err := emailStore.List(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("ERROR: Failed to list subscribers for broadcast: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not list subscribers."})
+		return
+	}
+
+	recipients := make([]*broadcastRecipient, 0, len(records))
+	for _, rec := range records {
+		if rec.ConfirmedAt == nil {
+			continue
+		}
+		recipients = append(recipients, &broadcastRecipient{Email: rec.Email, Status: "pending"})
+	}
+
+	jobID, err := generateJobID()
+	if err != nil {
+		log.Printf("ERROR: Failed to generate broadcast job ID: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start broadcast."})
+		return
+	}
+
+	job := &broadcastJob{
+		ID:         jobID,
+		Subject:    req.Subject,
+		CreatedAt:  time.Now(),
+		Total:      len(recipients),
+		Remaining:  len(recipients),
+		Recipients: recipients,
+		Req:        req,
+	}
+
+	broadcastJobsMutex.Lock()
+	broadcastJobs[jobID] = job
+	broadcastJobsMutex.Unlock()
+
+	if err := persistBroadcastJob(job); err != nil {
+		log.Printf("WARN: Failed to persist broadcast job %s: %v", job.ID, err)
+	}
+
+	spawnBroadcastJob(job)
+
+	c.JSON(http.StatusAccepted, gin.H{"job_id": jobID, "total": job.Total})
+}
+
+// broadcastStatusHandler reports the progress of a previously started job.
+func broadcastStatusHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	broadcastJobsMutex.Lock()
+	job, ok := broadcastJobs[id]
+	broadcastJobsMutex.Unlock()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown broadcast job."})
+		return
+	}
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	c.JSON(http.StatusOK, gin.H{
+		"id":        job.ID,
+		"subject":   job.Subject,
+		"total":     job.Total,
+		"sent":      job.Sent,
+		"failed":    job.Failed,
+		"remaining": job.Remaining,
+	})
+}
+
+// broadcastRetryHandler re-queues a job's "failed" recipients and resumes
+// sending, so an admin can recover a job that crashed partway through or
+// hit transient SMTP errors without re-mailing recipients who already
+// received it. Recipients still "pending" from an interrupted run are sent
+// along with the re-queued ones; a job with no "failed" recipient is not
+// re-run.
+func broadcastRetryHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	broadcastJobsMutex.Lock()
+	job, ok := broadcastJobs[id]
+	broadcastJobsMutex.Unlock()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown broadcast job."})
+		return
+	}
+
+	job.mutex.Lock()
+	var retried int
+	for _, recipient := range job.Recipients {
+		if recipient.Status == "failed" {
+			recipient.Status = "pending"
+			retried++
+		}
+	}
+	job.Failed -= retried
+	job.Remaining += retried
+	job.mutex.Unlock()
+
+	if retried == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "No failed recipients to retry.", "retried": 0})
+		return
+	}
+
+	spawnBroadcastJob(job)
+
+	c.JSON(http.StatusAccepted, gin.H{"job_id": job.ID, "retried": retried})
+}
+
+// runBroadcastJob fans the job's recipients out across a worker pool,
+// rate-limited so we don't exceed the mail provider's send quota, and
+// persists progress so a crash can be resumed from the job log.
+func runBroadcastJob(job *broadcastJob) {
+	limiter := rate.NewLimiter(rate.Limit(broadcastRatePerSec), broadcastRatePerSec)
+
+	work := make(chan *broadcastRecipient)
+	var wg sync.WaitGroup
+	for i := 0; i < broadcastWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for recipient := range work {
+				if err := limiter.Wait(context.Background()); err != nil {
+					continue
+				}
+				sendBroadcastToRecipient(job, recipient)
+			}
+		}()
+	}
+
+	for _, recipient := range job.Recipients {
+		// On a retry run, recipients already marked "sent" must not be
+		// re-mailed.
+		if recipient.Status == "sent" {
+			continue
+		}
+		work <- recipient
+	}
+	close(work)
+	wg.Wait()
+}
+
+// sendBroadcastToRecipient renders the per-recipient template vars, sends
+// the mail, and updates the job's progress counters.
+func sendBroadcastToRecipient(job *broadcastJob, recipient *broadcastRecipient) {
+	data := broadcastTemplateData(recipient.Email, job.Req.TemplateVars)
+
+	textBody, err := renderBroadcastBody(job.Req.TextBody, data)
+	if err != nil {
+		log.Printf("ERROR: Failed to render broadcast text_body for %s: %v", recipient.Email, err)
+		markBroadcastResult(job, recipient, false)
+		return
+	}
+
+	var htmlBody string
+	if job.Req.HTMLBody != "" {
+		htmlBody, err = renderBroadcastHTML(job.Req.HTMLBody, data)
+		if err != nil {
+			log.Printf("ERROR: Failed to render broadcast html_body for %s: %v", recipient.Email, err)
+			markBroadcastResult(job, recipient, false)
+			return
+		}
+	}
+
+	headers := unsubscribeHeaders(recipient.Email)
+	err = sendWithBackoff(activeMailer, mailJob{
+		to:       recipient.Email,
+		subject:  job.Subject,
+		body:     textBody,
+		htmlBody: htmlBody,
+		headers:  headers,
+	})
+	markBroadcastResult(job, recipient, err == nil)
+}
+
+// renderBroadcastBody substitutes {{.Email}}, {{.UnsubscribeURL}} and any
+// user-supplied template_vars into the text body.
+func renderBroadcastBody(textBody string, data map[string]string) (string, error) {
+	tmpl, err := texttemplate.New("broadcast").Parse(textBody)
+	if err != nil {
+		return "", fmt.Errorf("invalid text_body template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render text_body: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// renderBroadcastHTML is the html/template counterpart of
+// renderBroadcastBody, used when a job carries an html_body.
+func renderBroadcastHTML(htmlBody string, data map[string]string) (string, error) {
+	tmpl, err := template.New("broadcast-html").Parse(htmlBody)
+	if err != nil {
+		return "", fmt.Errorf("invalid html_body template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render html_body: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// broadcastTemplateData merges the fixed Email/UnsubscribeURL fields with
+// any caller-supplied template_vars, which take precedence on key clash.
+func broadcastTemplateData(email string, vars map[string]string) map[string]string {
+	data := map[string]string{
+		"Email":          email,
+		"UnsubscribeURL": unsubscribeURL(email),
+	}
+	for k, v := range vars {
+		data[k] = v
+	}
+	return data
+}
+
+func markBroadcastResult(job *broadcastJob, recipient *broadcastRecipient, success bool) {
+	job.mutex.Lock()
+	if success {
+		recipient.Status = "sent"
+		job.Sent++
+	} else {
+		recipient.Status = "failed"
+		job.Failed++
+	}
+	job.Remaining--
+	job.mutex.Unlock()
+
+	if err := persistBroadcastJob(job); err != nil {
+		log.Printf("WARN: Failed to persist broadcast job %s: %v", job.ID, err)
+	}
+}
+
+// persistBroadcastJob writes the job's current state to BROADCAST_LOG_DIR so
+// an admin can inspect or re-run failed recipients after a crash.
+func persistBroadcastJob(job *broadcastJob) error {
+	if err := os.MkdirAll(broadcastLogDir, 0755); err != nil {
+		return err
+	}
+
+	job.mutex.Lock()
+	data, err := json.MarshalIndent(job, "", "  ")
+	job.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(broadcastLogDir, job.ID+".json")
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadBroadcastJobs restores broadcastJobs from BROADCAST_LOG_DIR on
+// startup, so jobs are still inspectable via broadcastStatusHandler and
+// retryable via broadcastRetryHandler after a restart.
+func loadBroadcastJobs() {
+	entries, err := os.ReadDir(broadcastLogDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("WARN: Could not read broadcast log dir %s: %v", broadcastLogDir, err)
+		}
+		return
+	}
+
+	broadcastJobsMutex.Lock()
+	defer broadcastJobsMutex.Unlock()
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		path := filepath.Join(broadcastLogDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("WARN: Could not read broadcast job %s: %v", path, err)
+			continue
+		}
+
+		var job broadcastJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			log.Printf("WARN: Could not parse broadcast job %s: %v", path, err)
+			continue
+		}
+		broadcastJobs[job.ID] = &job
+	}
+	log.Printf("Loaded %d broadcast job(s) from %s.", len(broadcastJobs), broadcastLogDir)
+}
+
+// generateJobID returns a random, hex-encoded broadcast job identifier.
+func generateJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
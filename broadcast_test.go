@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeMailer is a Mailer that always succeeds, recording each send.
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (m *fakeMailer) Send(to, _, _, _ string, _ map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+// TestBroadcastTemplateData covers the template substitution a broadcast
+// body goes through: the fixed Email/UnsubscribeURL fields are always
+// present, and caller-supplied template_vars take precedence on key clash.
+func TestBroadcastTemplateData(t *testing.T) {
+	host = "https://example.com"
+	secretToken = "test-secret"
+
+	data := broadcastTemplateData("a@example.com", map[string]string{
+		"Email":     "overridden@example.com",
+		"FirstName": "Ada",
+	})
+
+	if data["Email"] != "overridden@example.com" {
+		t.Errorf("template_vars should override the fixed Email field, got %q", data["Email"])
+	}
+	if data["FirstName"] != "Ada" {
+		t.Errorf("want FirstName %q, got %q", "Ada", data["FirstName"])
+	}
+	if data["UnsubscribeURL"] == "" {
+		t.Error("want a non-empty UnsubscribeURL")
+	}
+
+	rendered, err := renderBroadcastBody("Hi {{.FirstName}}, unsubscribe: {{.UnsubscribeURL}}", data)
+	if err != nil {
+		t.Fatalf("renderBroadcastBody: %v", err)
+	}
+	if rendered != "Hi Ada, unsubscribe: "+data["UnsubscribeURL"] {
+		t.Errorf("unexpected rendered body: %q", rendered)
+	}
+}
+
+// TestLoadBroadcastJobsRestoresPersistedJob covers the chunk0-4 fix: a job
+// log written by persistBroadcastJob must actually be read back on startup,
+// including the original request body, so it can be inspected and retried
+// after a crash.
+func TestLoadBroadcastJobsRestoresPersistedJob(t *testing.T) {
+	dir := t.TempDir()
+	oldDir := broadcastLogDir
+	broadcastLogDir = dir
+	defer func() { broadcastLogDir = oldDir }()
+
+	job := &broadcastJob{
+		ID:        "abc123",
+		Subject:   "Hello",
+		CreatedAt: time.Now(),
+		Total:     2,
+		Sent:      1,
+		Failed:    1,
+		Remaining: 0,
+		Recipients: []*broadcastRecipient{
+			{Email: "sent@example.com", Status: "sent"},
+			{Email: "failed@example.com", Status: "failed"},
+		},
+		Req: BroadcastRequest{Subject: "Hello", TextBody: "Hi {{.Email}}"},
+	}
+	if err := persistBroadcastJob(job); err != nil {
+		t.Fatalf("persistBroadcastJob: %v", err)
+	}
+
+	broadcastJobsMutex.Lock()
+	broadcastJobs = make(map[string]*broadcastJob)
+	broadcastJobsMutex.Unlock()
+
+	loadBroadcastJobs()
+
+	broadcastJobsMutex.Lock()
+	restored, ok := broadcastJobs["abc123"]
+	broadcastJobsMutex.Unlock()
+	if !ok {
+		t.Fatal("loadBroadcastJobs did not restore the persisted job")
+	}
+	if restored.Req.TextBody != "Hi {{.Email}}" {
+		t.Errorf("want restored job to carry its original request body, got %q", restored.Req.TextBody)
+	}
+	if len(restored.Recipients) != 2 {
+		t.Fatalf("want 2 restored recipients, got %d", len(restored.Recipients))
+	}
+}
+
+// TestBroadcastRetryHandlerRequeuesFailedOnly covers the retry endpoint:
+// only "failed" recipients move back to "pending", "sent" recipients are
+// left alone so they aren't re-mailed.
+func TestBroadcastRetryHandlerRequeuesFailedOnly(t *testing.T) {
+	oldWorkers, oldRate := broadcastWorkers, broadcastRatePerSec
+	broadcastWorkers, broadcastRatePerSec = 1, 1000
+	defer func() { broadcastWorkers, broadcastRatePerSec = oldWorkers, oldRate }()
+
+	oldMailer := activeMailer
+	mailer := &fakeMailer{}
+	activeMailer = mailer
+	defer func() { activeMailer = oldMailer }()
+
+	// Run the job synchronously instead of spawning it in the background, so
+	// the assertions below can read job state without racing the worker
+	// pool, and so broadcastLogDir isn't restored while runBroadcastJob is
+	// still persisting to it.
+	oldSpawn := spawnBroadcastJob
+	spawnBroadcastJob = func(job *broadcastJob) { runBroadcastJob(job) }
+	defer func() { spawnBroadcastJob = oldSpawn }()
+
+	oldDir := broadcastLogDir
+	broadcastLogDir = t.TempDir()
+	defer func() { broadcastLogDir = oldDir }()
+
+	job := &broadcastJob{
+		ID:      "retry-me",
+		Subject: "Hello",
+		Total:   2,
+		Failed:  1,
+		Recipients: []*broadcastRecipient{
+			{Email: "sent@example.com", Status: "sent"},
+			{Email: "failed@example.com", Status: "failed"},
+		},
+		Req: BroadcastRequest{Subject: "Hello", TextBody: "Hi"},
+	}
+	broadcastJobsMutex.Lock()
+	broadcastJobs = map[string]*broadcastJob{"retry-me": job}
+	broadcastJobsMutex.Unlock()
+
+	router := gin.New()
+	router.POST("/coming-soon/broadcast/:id/retry", broadcastRetryHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/coming-soon/broadcast/retry-me/retry", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("want status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body["retried"] != float64(1) {
+		t.Errorf("want retried=1, got %v", body["retried"])
+	}
+
+	// spawnBroadcastJob was overridden above to run synchronously, so the
+	// re-run has already finished by the time ServeHTTP returns.
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	if job.Recipients[1].Status != "sent" {
+		t.Errorf("want the retried recipient to end up sent, got %q", job.Recipients[1].Status)
+	}
+	if job.Recipients[0].Status != "sent" {
+		t.Errorf("retry must not touch an already-sent recipient, got %q", job.Recipients[0].Status)
+	}
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	if len(mailer.sent) != 1 || mailer.sent[0] != "failed@example.com" {
+		t.Errorf("want only the failed recipient re-mailed, got %v", mailer.sent)
+	}
+}
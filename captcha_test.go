@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func resetPowChallenges() {
+	powChallengesMutex.Lock()
+	powChallenges = make(map[string]*powChallenge)
+	powChallengesMutex.Unlock()
+}
+
+// solvePow brute-forces a nonce satisfying difficulty, for use as test
+// fixtures. Real clients do the same search client-side.
+func solvePow(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		nonce := strconv.Itoa(i)
+		sum := sha256.Sum256([]byte(challenge + nonce))
+		if leadingZeroBits(sum[:]) >= difficulty {
+			return nonce
+		}
+	}
+}
+
+// TestVerifyPowSolutionAcceptsValidProof covers the difficulty check end to
+// end: a nonce that actually meets the required leading-zero-bit count is
+// accepted and the challenge is consumed.
+func TestVerifyPowSolutionAcceptsValidProof(t *testing.T) {
+	resetPowChallenges()
+	powDifficulty = 4
+
+	challenge := "fixed-test-challenge"
+	powChallengesMutex.Lock()
+	powChallenges[challenge] = &powChallenge{expiresAt: time.Now().Add(time.Minute)}
+	powChallengesMutex.Unlock()
+
+	nonce := solvePow(challenge, powDifficulty)
+	if err := verifyPowSolution(challenge, nonce); err != nil {
+		t.Fatalf("want a valid proof to be accepted, got %v", err)
+	}
+
+	// The same challenge must not be replayable.
+	if err := verifyPowSolution(challenge, nonce); err == nil {
+		t.Error("want a consumed challenge to be rejected on replay")
+	}
+}
+
+// TestVerifyPowSolutionRejectsInsufficientDifficulty covers a nonce that
+// doesn't meet the configured difficulty.
+func TestVerifyPowSolutionRejectsInsufficientDifficulty(t *testing.T) {
+	resetPowChallenges()
+	powDifficulty = 32 // unreasonably hard; "a" certainly won't satisfy it
+
+	challenge := "another-challenge"
+	powChallengesMutex.Lock()
+	powChallenges[challenge] = &powChallenge{expiresAt: time.Now().Add(time.Minute)}
+	powChallengesMutex.Unlock()
+
+	if err := verifyPowSolution(challenge, "a"); err == nil {
+		t.Error("want an insufficient proof to be rejected")
+	}
+}
+
+// TestVerifyPowSolutionRejectsExpiredChallenge covers expirePowChallengesLoop's
+// counterpart check: a challenge past its TTL must not be solvable even with
+// a correct nonce.
+func TestVerifyPowSolutionRejectsExpiredChallenge(t *testing.T) {
+	resetPowChallenges()
+	powDifficulty = 1
+
+	challenge := "expired-challenge"
+	powChallengesMutex.Lock()
+	powChallenges[challenge] = &powChallenge{expiresAt: time.Now().Add(-time.Minute)}
+	powChallengesMutex.Unlock()
+
+	nonce := solvePow(challenge, powDifficulty)
+	if err := verifyPowSolution(challenge, nonce); err == nil {
+		t.Error("want an expired challenge to be rejected")
+	}
+}
+
+// TestVerifyPowSolutionRejectsUnknownChallenge covers a challenge value that
+// was never issued by powChallengeHandler.
+func TestVerifyPowSolutionRejectsUnknownChallenge(t *testing.T) {
+	resetPowChallenges()
+
+	if err := verifyPowSolution("never-issued", "a"); err == nil {
+		t.Error("want an unknown challenge to be rejected")
+	}
+	if err := verifyPowSolution("", ""); err == nil {
+		t.Error("want a missing challenge/nonce to be rejected")
+	}
+}
+
+// TestVerifyCaptchaTokenTimesOutOnSlowProvider covers the bounded HTTP
+// client: a provider that never responds must not hang the request
+// goroutine indefinitely.
+func TestVerifyCaptchaTokenTimesOutOnSlowProvider(t *testing.T) {
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+	// Unblock the handler before closing the server: Close waits for the
+	// in-flight (if timed-out client-side) connection to finish.
+	defer server.Close()
+	defer close(blocked)
+
+	oldProvider, oldURL, oldClient := captchaProvider, hcaptchaVerifyURL, captchaHTTPClient
+	captchaProvider = "hcaptcha"
+	hcaptchaVerifyURL = server.URL
+	captchaHTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+	defer func() {
+		captchaProvider, hcaptchaVerifyURL, captchaHTTPClient = oldProvider, oldURL, oldClient
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- verifyCaptchaToken("some-token", "1.2.3.4") }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("want a timeout error from a provider that never responds")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("verifyCaptchaToken did not time out against an unresponsive provider")
+	}
+}
+
+// TestLeadingZeroBits covers the bit-counting helper the difficulty check
+// depends on.
+func TestLeadingZeroBits(t *testing.T) {
+	cases := []struct {
+		b    []byte
+		want int
+	}{
+		{[]byte{0xFF}, 0},
+		{[]byte{0x7F}, 1},
+		{[]byte{0x00, 0xFF}, 8},
+		{[]byte{0x00, 0x00}, 16},
+		{[]byte{0x01}, 7},
+	}
+	for _, c := range cases {
+		if got := leadingZeroBits(c.b); got != c.want {
+			t.Errorf("leadingZeroBits(%v) = %d, want %d", c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// countingMailer is a Mailer whose Send fails a fixed number of times before
+// succeeding, for exercising sendWithBackoff's retry loop.
+type countingMailer struct {
+	mu        sync.Mutex
+	failTimes int
+	sendCount int
+	lastTo    string
+}
+
+func (m *countingMailer) Send(to, _, _, _ string, _ map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendCount++
+	m.lastTo = to
+	if m.sendCount <= m.failTimes {
+		return fmt.Errorf("simulated SMTP failure")
+	}
+	return nil
+}
+
+// TestSendWithBackoffRetriesUntilSuccess covers a mailer that fails a couple
+// of times before succeeding: sendWithBackoff must retry rather than give up
+// early.
+func TestSendWithBackoffRetriesUntilSuccess(t *testing.T) {
+	oldInitial, oldMax := initialBackoff, maxBackoff
+	initialBackoff, maxBackoff = time.Millisecond, time.Millisecond
+	defer func() { initialBackoff, maxBackoff = oldInitial, oldMax }()
+
+	mailer := &countingMailer{failTimes: 2}
+	if err := sendWithBackoff(mailer, mailJob{to: "a@example.com"}); err != nil {
+		t.Fatalf("sendWithBackoff: %v", err)
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	if mailer.sendCount != 3 {
+		t.Errorf("want 3 send attempts (2 failures + 1 success), got %d", mailer.sendCount)
+	}
+}
+
+// TestSendWithBackoffGivesUpAfterMaxAttempts covers a mailer that never
+// succeeds: sendWithBackoff must stop at maxSendAttempts and return the last
+// error rather than retrying forever.
+func TestSendWithBackoffGivesUpAfterMaxAttempts(t *testing.T) {
+	oldInitial, oldMax := initialBackoff, maxBackoff
+	initialBackoff, maxBackoff = time.Millisecond, time.Millisecond
+	defer func() { initialBackoff, maxBackoff = oldInitial, oldMax }()
+
+	mailer := &countingMailer{failTimes: maxSendAttempts + 1}
+	if err := sendWithBackoff(mailer, mailJob{to: "a@example.com"}); err == nil {
+		t.Fatal("want an error when every attempt fails")
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	if mailer.sendCount != maxSendAttempts {
+		t.Errorf("want exactly %d send attempts, got %d", maxSendAttempts, mailer.sendCount)
+	}
+}
+
+// TestQueueMailDropsWhenFull covers the chunk0-1 fix: queueMail must not
+// block the caller when mailQueue is already at capacity, and must count the
+// drop instead of silently losing it.
+func TestQueueMailDropsWhenFull(t *testing.T) {
+	oldQueue := mailQueue
+	mailQueue = make(chan mailJob, 1)
+	defer func() { mailQueue = oldQueue }()
+
+	before := testutil.ToFloat64(mailQueueDroppedTotal)
+
+	queueMail("first@example.com", "s", "b", nil)
+
+	done := make(chan struct{})
+	go func() {
+		queueMail("second@example.com", "s", "b", nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queueMail blocked on a full queue instead of dropping")
+	}
+
+	if got := testutil.ToFloat64(mailQueueDroppedTotal); got != before+1 {
+		t.Errorf("want mailQueueDroppedTotal to increase by 1, got %v -> %v", before, got)
+	}
+
+	<-mailQueue // drain the one job that did fit, so the channel isn't leaked
+}
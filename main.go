@@ -1,39 +1,43 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"net/mail"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+
+	"github.com/Nawy/coming-soon-service/store"
 )
 
+const eventSignup = "signup"
+const eventListEmails = "list_emails"
+const eventAuth = "auth"
+
 var (
-	// The file where emails will be stored - can be configured via environment variable
-	emailFilePath string
 	// The secret token for the protected endpoint - loaded from environment variable
 	secretToken string
 	host        string
 )
 
-var (
-	// emailSet acts as our in-memory set for O(1) lookups.
-	// map[string]struct{} is the idiomatic way to create a set in Go.
-	emailSet = make(map[string]struct{})
-	// mutex protects concurrent access to both emailSet and emailFilePath
-	mutex = &sync.Mutex{}
-)
+// emailStore is the persistence backend selected via STORE_DSN. It replaces
+// the old in-memory emailSet + flat-file pair with a pluggable Store.
+var emailStore store.Store
 
 // EmailRequest is the expected JSON structure for the POST request.
+// CaptchaToken, Challenge and Nonce are only required when bot mitigation
+// is enabled via CAPTCHA_PROVIDER or POW_DIFFICULTY; see captcha.go.
 type EmailRequest struct {
-	Email string `json:"email" binding:"required"`
+	Email        string `json:"email" binding:"required"`
+	CaptchaToken string `json:"captcha_token"`
+	Challenge    string `json:"challenge"`
+	Nonce        string `json:"nonce"`
 }
 
 func main() {
@@ -48,26 +52,66 @@ func main() {
 		log.Fatal("ERROR: SECRET_TOKEN environment variable is not set")
 	}
 
-	// Set email file path from environment variable, default to "emails.txt"
-	emailFilePath = os.Getenv("EMAIL_FILE_PATH")
-	if emailFilePath == "" {
-		emailFilePath = "emails.txt"
+	// Set the storage DSN from environment variable, default to the flat-file
+	// backend for backward compatibility.
+	storeDSN := os.Getenv("STORE_DSN")
+	if storeDSN == "" {
+		storeDSN = "file://emails.txt"
 	}
-	log.Printf("Using email file path: %s", emailFilePath)
+	log.Printf("Using store DSN: %s", storeDSN)
 
-	// 1. Load existing emails from the file into the in-memory set on startup.
-	if err := loadEmailsFromFile(); err != nil {
-		log.Printf("Warning: Could not load emails from %s: %v. Starting with an empty set.", emailFilePath, err)
+	// 1. Open the configured store.
+	var err error
+	emailStore, err = store.Open(storeDSN)
+	if err != nil {
+		log.Fatalf("ERROR: Could not open store: %v", err)
+	}
+	count, err := emailStore.Count(context.Background())
+	if err != nil {
+		log.Fatalf("ERROR: Could not read store: %v", err)
 	}
-	log.Printf("Service started, loaded %d emails.", len(emailSet))
+	log.Printf("Service started, loaded %d emails.", count)
+
+	// 1b. Set up the mailer used for double opt-in confirmation emails and
+	// start the background worker that retries failed sends.
+	mailer := loadMailer()
+	startMailWorker(mailer)
+	go expirePendingLoop()
+
+	// 1c. Load optional CAPTCHA/proof-of-work bot mitigation, disabled by
+	// default.
+	loadBotMitigation()
+	go expirePowChallengesLoop()
+
+	// 1d. Evict idle per-IP rate limiters so a flood from many distinct
+	// source IPs can't grow ipLimiters without bound.
+	go expireIPLimitersLoop()
+
+	// 1e. Restore in-flight/crashed broadcast jobs from BROADCAST_LOG_DIR so
+	// they remain inspectable and retryable after a restart.
+	loadBroadcastJobs()
 
 	// 2. Set up the Gin router.
 	router := gin.Default()
 	setupCors(router, host)
 
 	// 3. Define endpoints.
-	// POST /coming-soon (public)
-	router.POST("/coming-soon", postEmailHandler)
+	// GET /metrics (public) - Prometheus scrape target.
+	router.GET("/metrics", gin.WrapH(metricsHandler()))
+
+	// POST /coming-soon (public, rate-limited per IP)
+	router.POST("/coming-soon", perIPRateLimit(), postEmailHandler)
+
+	// GET /coming-soon/challenge (public) - issues a proof-of-work
+	// challenge when POW_DIFFICULTY is configured.
+	router.GET("/coming-soon/challenge", powChallengeHandler)
+
+	// GET /coming-soon/confirm (public) - completes the double opt-in flow.
+	router.GET("/coming-soon/confirm", confirmHandler)
+
+	// Unsubscribe (public, no auth - RFC 8058 one-click unsubscribe).
+	router.GET("/coming-soon/unsubscribe", unsubscribeHandler)
+	router.POST("/coming-soon/unsubscribe", unsubscribeHandler)
 
 	// GET /coming-soon (protected by middleware)
 	// We create a group to apply the middleware only to specific routes.
@@ -75,6 +119,9 @@ func main() {
 	protected.Use(authMiddleware())
 	{
 		protected.GET("/coming-soon", getEmailsHandler)
+		protected.POST("/coming-soon/broadcast", broadcastHandler)
+		protected.GET("/coming-soon/broadcast/:id", broadcastStatusHandler)
+		protected.POST("/coming-soon/broadcast/:id/retry", broadcastRetryHandler)
 	}
 
 	// 4. Run the server.
@@ -86,65 +133,106 @@ func main() {
 
 // --- Handlers ---
 
-// postEmailHandler handles new email submissions.
+// postEmailHandler handles new email submissions by starting the double
+// opt-in flow: the email is held in a pending state until the recipient
+// clicks the confirmation link we send them.
 func postEmailHandler(c *gin.Context) {
+	start := time.Now()
+	remoteIP := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	audit := func(emailHash, status string) {
+		latencyMs := time.Since(start).Milliseconds()
+		logAudit(eventSignup, emailHash, remoteIP, userAgent, status, latencyMs)
+		signupsTotal.WithLabelValues(status).Inc()
+		handlerLatencySeconds.WithLabelValues("postEmailHandler").Observe(time.Since(start).Seconds())
+	}
+
 	var req EmailRequest
 
 	// 1. Bind and validate the incoming JSON.
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request. 'email' field is required."})
+		audit("", "invalid")
 		return
 	}
 
 	// 2. Validate the email format using the standard library.
 	if _, err := mail.ParseAddress(req.Email); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format."})
+		audit(hashEmail(req.Email), "invalid")
 		return
 	}
 
 	// 3. Normalize the email to lowercase.
 	email := strings.ToLower(req.Email)
+	emailHash := hashEmail(email)
 
-	// 4. Lock the mutex to ensure thread-safety for checking the map
-	//    and writing to the file.
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	// 5. Check if the email already exists in the set.
-	if _, exists := emailSet[email]; exists {
-		c.JSON(http.StatusConflict, gin.H{"message": "Email already registered."})
+	// 3b. Bot mitigation, if configured. Disabled by default.
+	if err := verifyBotMitigation(&req, remoteIP); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Bot verification failed: %v", err)})
+		audit(emailHash, "invalid")
 		return
 	}
 
-	// 6. Add the new email to the set.
-	emailSet[email] = struct{}{}
-
-	// 7. Update the file on disk with all records from the map.
-	if err := saveEmailsToFile(); err != nil {
-		// If saving fails, roll back the in-memory change for consistency.
-		delete(emailSet, email)
-		log.Printf("ERROR: Failed to save email file: %v", err)
+	// 4. Record the signup. Add reports store.ErrExists only if the email is
+	//    already confirmed; an unconfirmed one is refreshed so a new token
+	//    can be issued below.
+	if err := emailStore.Add(c.Request.Context(), email, remoteIP, userAgent); err != nil {
+		if err == store.ErrExists {
+			c.JSON(http.StatusConflict, gin.H{"message": "Email already registered."})
+			audit(emailHash, "duplicate")
+			return
+		}
+		log.Printf("ERROR: Failed to save email: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save email. Please try again."})
+		audit(emailHash, "invalid")
+		return
+	}
+
+	// 5. Generate a confirmation token and record the pending subscription.
+	token, err := generateConfirmationToken()
+	if err != nil {
+		log.Printf("ERROR: Failed to generate confirmation token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start subscription. Please try again."})
+		audit(emailHash, "invalid")
 		return
 	}
 
-	// 8. Success!
-	c.JSON(http.StatusCreated, gin.H{"message": "Email registered successfully."})
+	pendingMutex.Lock()
+	pendingSet[token] = &pendingSubscription{
+		email:     email,
+		expiresAt: time.Now().Add(pendingTTL),
+	}
+	pendingMutex.Unlock()
+
+	// 6. Queue the confirmation email; a flaky SMTP server is retried in the
+	//    background instead of failing this request.
+	queueMail(email, "Confirm your subscription", confirmationEmailBody(email, token), unsubscribeHeaders(email))
+
+	// 7. Success! The subscription is pending until the email is confirmed.
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation email sent. Please check your inbox."})
+	audit(emailHash, "new")
 }
 
-// getEmailsHandler returns the list of all registered emails.
+// getEmailsHandler returns every recorded signup with its metadata.
+// Unsubscribed addresses are omitted unless ?include_unsubscribed=true.
 func getEmailsHandler(c *gin.Context) {
-	// Lock for read to prevent concurrent map modification while iterating.
-	mutex.Lock()
-	defer mutex.Unlock()
+	start := time.Now()
+	defer func() {
+		handlerLatencySeconds.WithLabelValues("getEmailsHandler").Observe(time.Since(start).Seconds())
+	}()
 
-	// Convert the set (map keys) into a list (slice).
-	emails := make([]string, 0, len(emailSet))
-	for email := range emailSet {
-		emails = append(emails, email)
+	records, err := emailStore.List(c.Request.Context(), parseIncludeUnsubscribed(c))
+	if err != nil {
+		log.Printf("ERROR: Failed to list emails: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not list emails."})
+		logAudit(eventListEmails, "", c.ClientIP(), c.Request.UserAgent(), "error", time.Since(start).Milliseconds())
+		return
 	}
 
-	c.JSON(http.StatusOK, emails)
+	c.JSON(http.StatusOK, records)
+	logAudit(eventListEmails, "", c.ClientIP(), c.Request.UserAgent(), "ok", time.Since(start).Milliseconds())
 }
 
 // --- Middleware ---
@@ -152,78 +240,32 @@ func getEmailsHandler(c *gin.Context) {
 // authMiddleware checks for the presence and correctness of the secret header.
 func authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		start := time.Now()
 		token := c.GetHeader("X-Secret-Token")
 
 		if token == "" {
+			authFailuresTotal.Inc()
+			logAudit(eventAuth, "", c.ClientIP(), c.Request.UserAgent(), "missing_token", time.Since(start).Milliseconds())
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing X-Secret-Token header"})
 			return
 		}
 
 		if token != secretToken {
+			authFailuresTotal.Inc()
+			logAudit(eventAuth, "", c.ClientIP(), c.Request.UserAgent(), "invalid_token", time.Since(start).Milliseconds())
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid secret token"})
 			return
 		}
 
 		// Token is valid, proceed to the handler.
+		logAudit(eventAuth, "", c.ClientIP(), c.Request.UserAgent(), "ok", time.Since(start).Milliseconds())
 		c.Next()
 	}
 }
 
-// --- File I/O Helpers ---
-
-// loadEmailsFromFile reads the email file from disk into the in-memory set.
-func loadEmailsFromFile() error {
-	// Lock to prevent concurrent access during initialization.
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	file, err := os.Open(emailFilePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // File doesn't exist yet, which is fine.
-		}
-		return err // Other error (e.g., permissions)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		email := strings.TrimSpace(scanner.Text())
-		if email != "" {
-			// Add the normalized email to the set
-			emailSet[strings.ToLower(email)] = struct{}{}
-		}
-	}
-	return scanner.Err()
-}
-
-// saveEmailsToFile rewrites the entire email file with the current set.
-// It assumes the mutex is already held by the caller.
-func saveEmailsToFile() error {
-	// Open the file with options: Write-only, Create if not exist, Truncate (clear) on open.
-	file, err := os.OpenFile(emailFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("could not open file for writing: %w", err)
-	}
-	defer file.Close()
-
-	// Use a buffered writer for efficiency.
-	writer := bufio.NewWriter(file)
-
-	for email := range emailSet {
-		// Write each email followed by a newline.
-		if _, err := writer.WriteString(email + "\n"); err != nil {
-			return fmt.Errorf("could not write email to file: %w", err)
-		}
-	}
-
-	// Flush the buffer to ensure all data is written to disk.
-	return writer.Flush()
-}
-
 func setupCors(r *gin.Engine, host string) {
 	r.Use(cors.New(cors.Config{
-		// ðŸš¨ Allow your frontend origin
+		// 🚨 Allow your frontend origin
 		AllowOrigins: []string{host},
 
 		// Specify which methods are allowed
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newAuthTestRouter wires authMiddleware in front of a no-op handler, same as
+// the protected group in main's route setup.
+func newAuthTestRouter() *gin.Engine {
+	router := gin.New()
+	router.GET("/protected", authMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return router
+}
+
+// TestAuthMiddlewareAuditsEveryOutcome covers the three outcomes of
+// authMiddleware - missing token, wrong token, valid token - and checks that
+// each one lands an audit event, not just a metric increment.
+func TestAuthMiddlewareAuditsEveryOutcome(t *testing.T) {
+	secretToken = "test-secret"
+	oldLogger := auditLogger
+	defer func() { auditLogger = oldLogger }()
+
+	cases := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantAudit  string
+	}{
+		{"missing", "", http.StatusUnauthorized, "missing_token"},
+		{"invalid", "wrong", http.StatusForbidden, "invalid_token"},
+		{"valid", "test-secret", http.StatusOK, "ok"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			auditLogger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+			router := newAuthTestRouter()
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tc.header != "" {
+				req.Header.Set("X-Secret-Token", tc.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("want status %d, got %d: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+
+			logged := buf.String()
+			if !strings.Contains(logged, `"event":"auth"`) {
+				t.Errorf("want an auth audit event, got %q", logged)
+			}
+			if !strings.Contains(logged, `"status":"`+tc.wantAudit+`"`) {
+				t.Errorf("want audit status %q, got %q", tc.wantAudit, logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	signupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "coming_soon_signups_total",
+		Help: "Total number of POST /coming-soon submissions by outcome.",
+	}, []string{"status"}) // status: new, duplicate, invalid
+
+	authFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "coming_soon_auth_failures_total",
+		Help: "Total number of requests rejected by authMiddleware.",
+	})
+
+	mailQueueDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "coming_soon_mail_queue_dropped_total",
+		Help: "Total number of emails dropped because mailQueue was full.",
+	})
+
+	handlerLatencySeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "coming_soon_handler_latency_seconds",
+		Help:    "Latency of HTTP handlers, in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"handler"})
+)
+
+// metricsHandler exposes the Prometheus registry at GET /metrics.
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// signupRateLimitPerMinute caps how many POST /coming-soon requests a
+// single IP may make, configurable via RATE_LIMIT_PER_MINUTE. This guards
+// against list-poisoning floods.
+var signupRateLimitPerMinute = envInt("RATE_LIMIT_PER_MINUTE", 10)
+
+// ipLimiterTTL is how long an IP's limiter may sit idle before
+// expireIPLimitersLoop evicts it. Without this, a flood from a large
+// number of distinct source IPs - the exact threat this feature mitigates
+// - would grow ipLimiters without bound.
+const ipLimiterTTL = 10 * time.Minute
+
+// ipLimiter pairs a token bucket with the last time it was used, so the
+// sweep loop can evict entries that have gone idle.
+type ipLimiter struct {
+	limiter    *rate.Limiter
+	lastSeenAt time.Time
+}
+
+// ipLimiters holds one token bucket per client IP, created lazily.
+var (
+	ipLimiters      = make(map[string]*ipLimiter)
+	ipLimitersMutex sync.Mutex
+)
+
+func limiterForIP(ip string) *rate.Limiter {
+	ipLimitersMutex.Lock()
+	defer ipLimitersMutex.Unlock()
+
+	entry, ok := ipLimiters[ip]
+	if !ok {
+		// A burst equal to the per-minute quota lets a client use its
+		// whole budget immediately, then refills at the per-minute rate.
+		entry = &ipLimiter{limiter: rate.NewLimiter(rate.Limit(float64(signupRateLimitPerMinute)/60), signupRateLimitPerMinute)}
+		ipLimiters[ip] = entry
+	}
+	entry.lastSeenAt = time.Now()
+	return entry.limiter
+}
+
+// expireIPLimitersLoop periodically sweeps ipLimiters for entries idle past
+// ipLimiterTTL. It runs for the lifetime of the process and should be
+// started with `go`.
+func expireIPLimitersLoop() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		evictIdleIPLimiters(time.Now())
+	}
+}
+
+// evictIdleIPLimiters removes every ipLimiters entry not seen since before
+// now-ipLimiterTTL.
+func evictIdleIPLimiters(now time.Time) {
+	cutoff := now.Add(-ipLimiterTTL)
+	ipLimitersMutex.Lock()
+	defer ipLimitersMutex.Unlock()
+	for ip, entry := range ipLimiters {
+		if entry.lastSeenAt.Before(cutoff) {
+			delete(ipLimiters, ip)
+		}
+	}
+}
+
+// perIPRateLimit returns 429 with a Retry-After header once a client IP
+// exceeds signupRateLimitPerMinute requests per minute.
+func perIPRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter := limiterForIP(c.ClientIP())
+		if !limiter.Allow() {
+			c.Header("Retry-After", "60")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests. Please try again later."})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func resetIPLimiters() {
+	ipLimitersMutex.Lock()
+	ipLimiters = make(map[string]*ipLimiter)
+	ipLimitersMutex.Unlock()
+}
+
+// TestLimiterForIPReusesExistingLimiter ensures repeated callers of the same
+// IP share one token bucket, so its budget is actually enforced across
+// requests rather than reset on every call.
+func TestLimiterForIPReusesExistingLimiter(t *testing.T) {
+	resetIPLimiters()
+
+	first := limiterForIP("1.2.3.4")
+	second := limiterForIP("1.2.3.4")
+	if first != second {
+		t.Fatal("limiterForIP returned a different limiter for the same IP")
+	}
+}
+
+// TestEvictIdleIPLimiters covers the fix for chunk0-5: a public,
+// unauthenticated endpoint under a flood from many distinct source IPs must
+// not grow ipLimiters without bound.
+func TestEvictIdleIPLimiters(t *testing.T) {
+	resetIPLimiters()
+
+	limiterForIP("idle.ip")
+	limiterForIP("active.ip")
+
+	// Push "idle.ip" back in time so it looks stale, while "active.ip"
+	// stays fresh.
+	ipLimitersMutex.Lock()
+	ipLimiters["idle.ip"].lastSeenAt = time.Now().Add(-2 * ipLimiterTTL)
+	ipLimitersMutex.Unlock()
+
+	evictIdleIPLimiters(time.Now())
+
+	ipLimitersMutex.Lock()
+	defer ipLimitersMutex.Unlock()
+	if _, ok := ipLimiters["idle.ip"]; ok {
+		t.Error("evictIdleIPLimiters did not evict an idle entry")
+	}
+	if _, ok := ipLimiters["active.ip"]; !ok {
+		t.Error("evictIdleIPLimiters evicted a still-active entry")
+	}
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fileStore is the original flat-file backend, now storing one JSON record
+// per line instead of a bare email so metadata survives restarts. It keeps
+// everything in memory and rewrites the whole file on each mutation, which
+// is fine at the scale this service runs at.
+type fileStore struct {
+	path string
+
+	mutex   sync.Mutex
+	records map[string]*Record
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	fs := &fileStore{
+		path:    path,
+		records: make(map[string]*Record),
+	}
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func (fs *fileStore) load() error {
+	file, err := os.Open(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return fmt.Errorf("corrupt record in %s: %w", fs.path, err)
+		}
+		fs.records[rec.Email] = &rec
+	}
+	return scanner.Err()
+}
+
+// save rewrites the whole file from the in-memory records. Callers must
+// hold fs.mutex.
+func (fs *fileStore) save() error {
+	file, err := os.OpenFile(fs.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open file for writing: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, rec := range fs.records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("could not marshal record: %w", err)
+		}
+		if _, err := writer.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("could not write record: %w", err)
+		}
+	}
+	return writer.Flush()
+}
+
+func (fs *fileStore) Add(_ context.Context, email, sourceIP, userAgent string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	previous, exists := fs.records[email]
+	if exists && previous.ConfirmedAt != nil {
+		return ErrExists
+	}
+
+	fs.records[email] = &Record{
+		Email:     email,
+		CreatedAt: time.Now(),
+		SourceIP:  sourceIP,
+		UserAgent: userAgent,
+	}
+	if err := fs.save(); err != nil {
+		if exists {
+			fs.records[email] = previous
+		} else {
+			delete(fs.records, email)
+		}
+		return err
+	}
+	return nil
+}
+
+func (fs *fileStore) Exists(_ context.Context, email string) (bool, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	_, exists := fs.records[email]
+	return exists, nil
+}
+
+func (fs *fileStore) List(_ context.Context, includeUnsubscribed bool) ([]Record, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	out := make([]Record, 0, len(fs.records))
+	for _, rec := range fs.records {
+		if rec.UnsubscribedAt != nil && !includeUnsubscribed {
+			continue
+		}
+		out = append(out, *rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (fs *fileStore) Confirm(_ context.Context, email string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	rec, exists := fs.records[email]
+	if !exists {
+		return ErrNotFound
+	}
+	now := time.Now()
+	rec.ConfirmedAt = &now
+	return fs.save()
+}
+
+func (fs *fileStore) Unsubscribe(_ context.Context, email string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	rec, exists := fs.records[email]
+	if !exists {
+		return ErrNotFound
+	}
+	now := time.Now()
+	rec.UnsubscribedAt = &now
+	return fs.save()
+}
+
+func (fs *fileStore) Delete(_ context.Context, email string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if _, exists := fs.records[email]; !exists {
+		return ErrNotFound
+	}
+	delete(fs.records, email)
+	return fs.save()
+}
+
+func (fs *fileStore) Count(_ context.Context) (int, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	return len(fs.records), nil
+}
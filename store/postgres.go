@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS emails (
+	email            TEXT PRIMARY KEY,
+	created_at       TIMESTAMPTZ NOT NULL,
+	confirmed_at     TIMESTAMPTZ,
+	source_ip        TEXT,
+	user_agent       TEXT,
+	unsubscribed_at  TIMESTAMPTZ
+);
+`
+
+// postgresStore is a Store backed by jackc/pgx, used in deployments that
+// already run a shared Postgres instance.
+type postgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func newPostgresStore(dsn string) (*postgresStore, error) {
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("could not open postgres store: %w", err)
+	}
+
+	if _, err := pool.Exec(context.Background(), postgresSchema); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("could not migrate postgres store: %w", err)
+	}
+
+	return &postgresStore{pool: pool}, nil
+}
+
+func (s *postgresStore) Add(ctx context.Context, email, sourceIP, userAgent string) error {
+	// ON CONFLICT refreshes an unconfirmed record in place instead of
+	// failing, so a lost or expired confirmation token can be replaced by
+	// submitting the signup form again. The WHERE clause leaves confirmed
+	// records untouched; RowsAffected is then 0, which we surface as
+	// ErrExists.
+	tag, err := s.pool.Exec(ctx,
+		`INSERT INTO emails (email, created_at, source_ip, user_agent) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (email) DO UPDATE SET created_at = excluded.created_at, source_ip = excluded.source_ip, user_agent = excluded.user_agent
+		 WHERE emails.confirmed_at IS NULL`,
+		email, time.Now(), sourceIP, userAgent)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+func (s *postgresStore) Exists(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	err := s.pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM emails WHERE email = $1)`, email).Scan(&exists)
+	return exists, err
+}
+
+func (s *postgresStore) List(ctx context.Context, includeUnsubscribed bool) ([]Record, error) {
+	query := `SELECT email, created_at, confirmed_at, source_ip, user_agent, unsubscribed_at FROM emails`
+	if !includeUnsubscribed {
+		query += ` WHERE unsubscribed_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Record
+	for rows.Next() {
+		var rec Record
+		if err := rows.Scan(&rec.Email, &rec.CreatedAt, &rec.ConfirmedAt, &rec.SourceIP, &rec.UserAgent, &rec.UnsubscribedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) Confirm(ctx context.Context, email string) error {
+	tag, err := s.pool.Exec(ctx, `UPDATE emails SET confirmed_at = $1 WHERE email = $2`, time.Now(), email)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) Unsubscribe(ctx context.Context, email string) error {
+	tag, err := s.pool.Exec(ctx, `UPDATE emails SET unsubscribed_at = $1 WHERE email = $2`, time.Now(), email)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) Delete(ctx context.Context, email string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM emails WHERE email = $1`, email)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM emails`).Scan(&count)
+	return count, err
+}
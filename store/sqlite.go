@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS emails (
+	email            TEXT PRIMARY KEY,
+	created_at       TIMESTAMP NOT NULL,
+	confirmed_at     TIMESTAMP,
+	source_ip        TEXT,
+	user_agent       TEXT,
+	unsubscribed_at  TIMESTAMP
+);
+`
+
+// sqliteStore is a Store backed by modernc.org/sqlite, a CGo-free SQLite
+// driver. dataSource is the path to the database file.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(dataSource string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("could not open sqlite store: %w", err)
+	}
+	// SQLite only supports a single writer at a time.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not migrate sqlite store: %w", err)
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Add(ctx context.Context, email, sourceIP, userAgent string) error {
+	// ON CONFLICT refreshes an unconfirmed record in place instead of
+	// failing, so a lost or expired confirmation token can be replaced by
+	// submitting the signup form again. The WHERE clause leaves confirmed
+	// records untouched; RowsAffected is then 0, which we surface as
+	// ErrExists.
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO emails (email, created_at, source_ip, user_agent) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(email) DO UPDATE SET created_at = excluded.created_at, source_ip = excluded.source_ip, user_agent = excluded.user_agent
+		 WHERE confirmed_at IS NULL`,
+		email, time.Now(), sourceIP, userAgent)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+func (s *sqliteStore) Exists(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM emails WHERE email = ?)`, email).Scan(&exists)
+	return exists, err
+}
+
+func (s *sqliteStore) List(ctx context.Context, includeUnsubscribed bool) ([]Record, error) {
+	query := `SELECT email, created_at, confirmed_at, source_ip, user_agent, unsubscribed_at FROM emails`
+	if !includeUnsubscribed {
+		query += ` WHERE unsubscribed_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Record
+	for rows.Next() {
+		var rec Record
+		if err := rows.Scan(&rec.Email, &rec.CreatedAt, &rec.ConfirmedAt, &rec.SourceIP, &rec.UserAgent, &rec.UnsubscribedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqliteStore) Confirm(ctx context.Context, email string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE emails SET confirmed_at = ? WHERE email = ?`, time.Now(), email)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *sqliteStore) Unsubscribe(ctx context.Context, email string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE emails SET unsubscribed_at = ? WHERE email = ?`, time.Now(), email)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *sqliteStore) Delete(ctx context.Context, email string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM emails WHERE email = ?`, email)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res)
+}
+
+func (s *sqliteStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM emails`).Scan(&count)
+	return count, err
+}
+
+func checkRowsAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
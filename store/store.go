@@ -0,0 +1,74 @@
+// Package store defines the persistence layer for coming-soon signups and
+// provides the backends selected via the STORE_DSN environment variable.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrExists is returned by Add when the email is already present.
+var ErrExists = errors.New("email already exists")
+
+// ErrNotFound is returned by Confirm and Delete when the email is unknown.
+var ErrNotFound = errors.New("email not found")
+
+// Record is a single signup and its associated metadata.
+type Record struct {
+	Email          string     `json:"email"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+	SourceIP       string     `json:"source_ip,omitempty"`
+	UserAgent      string     `json:"user_agent,omitempty"`
+	UnsubscribedAt *time.Time `json:"unsubscribed_at,omitempty"`
+}
+
+// Store is the persistence contract the handlers in main.go depend on.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Add records a new signup. If email is already present but not yet
+	// confirmed, Add refreshes its created_at/source_ip/user_agent instead
+	// of failing, so a lost or expired confirmation token can always be
+	// replaced by submitting the signup form again. It returns ErrExists
+	// only if email is already confirmed.
+	Add(ctx context.Context, email, sourceIP, userAgent string) error
+	// Exists reports whether email has already been recorded.
+	Exists(ctx context.Context, email string) (bool, error)
+	// List returns every record, newest first. Unsubscribed records are
+	// omitted unless includeUnsubscribed is true.
+	List(ctx context.Context, includeUnsubscribed bool) ([]Record, error)
+	// Confirm marks email as confirmed. It returns ErrNotFound if the
+	// email was never added.
+	Confirm(ctx context.Context, email string) error
+	// Unsubscribe marks email as unsubscribed without deleting it, so it
+	// stops receiving mail but its signup history is retained. It returns
+	// ErrNotFound if the email was never added.
+	Unsubscribe(ctx context.Context, email string) error
+	// Delete removes email entirely.
+	Delete(ctx context.Context, email string) error
+	// Count returns the number of recorded emails.
+	Count(ctx context.Context) (int, error)
+}
+
+// Open builds the Store selected by dsn. The scheme determines the backend:
+//
+//	file://emails.txt          - flat-file backend (default)
+//	sqlite:///data/emails.db   - modernc.org/sqlite backend
+//	postgres://...             - jackc/pgx backend
+func Open(dsn string) (Store, error) {
+	switch {
+	case dsn == "":
+		return nil, fmt.Errorf("STORE_DSN must not be empty")
+	case strings.HasPrefix(dsn, "file://"):
+		return newFileStore(strings.TrimPrefix(dsn, "file://"))
+	case strings.HasPrefix(dsn, "sqlite://"):
+		return newSQLiteStore(strings.TrimPrefix(dsn, "sqlite://"))
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return newPostgresStore(dsn)
+	default:
+		return nil, fmt.Errorf("unrecognized STORE_DSN scheme: %q", dsn)
+	}
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// newTestStores returns one instance of every Store backend that can run
+// without an external service, so the shared behavior below is exercised
+// against each of them.
+func newTestStores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	fs, err := newFileStore(filepath.Join(t.TempDir(), "emails.txt"))
+	if err != nil {
+		t.Fatalf("newFileStore: %v", err)
+	}
+
+	sq, err := newSQLiteStore(filepath.Join(t.TempDir(), "emails.db"))
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+
+	return map[string]Store{"file": fs, "sqlite": sq}
+}
+
+// TestAddIsIdempotentForUnconfirmed covers the lockout regression fixed for
+// chunk0-2: a signup whose confirmation token was lost to expiry or a
+// process restart must be able to re-signup and get a fresh token instead
+// of being stuck behind ErrExists forever.
+func TestAddIsIdempotentForUnconfirmed(t *testing.T) {
+	ctx := context.Background()
+
+	for name, s := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Add(ctx, "a@example.com", "1.1.1.1", "ua-1"); err != nil {
+				t.Fatalf("first Add: %v", err)
+			}
+
+			// The token for this signup was lost (expired or the process
+			// restarted); a fresh POST must succeed, not return ErrExists.
+			if err := s.Add(ctx, "a@example.com", "2.2.2.2", "ua-2"); err != nil {
+				t.Fatalf("second Add on unconfirmed record: got %v, want nil", err)
+			}
+
+			records, err := s.List(ctx, true)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("want 1 record after re-signup, got %d", len(records))
+			}
+			if records[0].SourceIP != "2.2.2.2" {
+				t.Errorf("want refreshed source_ip %q, got %q", "2.2.2.2", records[0].SourceIP)
+			}
+		})
+	}
+}
+
+// TestAddRejectsConfirmed ensures Add still protects confirmed records:
+// only unconfirmed signups are replaceable.
+func TestAddRejectsConfirmed(t *testing.T) {
+	ctx := context.Background()
+
+	for name, s := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Add(ctx, "b@example.com", "1.1.1.1", "ua-1"); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+			if err := s.Confirm(ctx, "b@example.com"); err != nil {
+				t.Fatalf("Confirm: %v", err)
+			}
+
+			if err := s.Add(ctx, "b@example.com", "2.2.2.2", "ua-2"); err != ErrExists {
+				t.Fatalf("Add on confirmed record: got %v, want ErrExists", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pendingTTL is how long an unconfirmed signup stays valid before it is
+// swept away by expirePendingLoop.
+const pendingTTL = 24 * time.Hour
+
+// pendingSubscription is an email awaiting confirmation.
+type pendingSubscription struct {
+	email     string
+	expiresAt time.Time
+}
+
+var (
+	// pendingSet maps a confirmation token to the subscription awaiting it.
+	pendingSet   = make(map[string]*pendingSubscription)
+	pendingMutex = &sync.Mutex{}
+)
+
+// generateConfirmationToken returns a cryptographically random, hex-encoded
+// token suitable for use in a confirmation link.
+func generateConfirmationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("could not generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// confirmationEmailBody renders the plain-text body for the opt-in email.
+func confirmationEmailBody(email, token string) string {
+	link := fmt.Sprintf("%s/coming-soon/confirm?token=%s", host, token)
+	return fmt.Sprintf("Hi,\n\nPlease confirm your subscription by visiting the link below:\n\n%s\n\nIf you didn't request this, you can ignore this email.\n", link)
+}
+
+// confirmHandler promotes a pending subscription into the confirmed set
+// once its token is presented.
+func confirmHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'token' query parameter."})
+		return
+	}
+
+	pendingMutex.Lock()
+	pending, ok := pendingSet[token]
+	if !ok {
+		pendingMutex.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation token."})
+		return
+	}
+	if time.Now().After(pending.expiresAt) {
+		delete(pendingSet, token)
+		pendingMutex.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation token."})
+		return
+	}
+	delete(pendingSet, token)
+	pendingMutex.Unlock()
+
+	if err := emailStore.Confirm(c.Request.Context(), pending.email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not confirm email. Please try again."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email confirmed successfully."})
+}
+
+// expirePendingLoop periodically sweeps pendingSet for tokens past their TTL.
+// It runs for the lifetime of the process and should be started with `go`.
+func expirePendingLoop() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		pendingMutex.Lock()
+		for token, pending := range pendingSet {
+			if now.After(pending.expiresAt) {
+				delete(pendingSet, token)
+			}
+		}
+		pendingMutex.Unlock()
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Nawy/coming-soon-service/store"
+)
+
+func resetPendingSet() {
+	pendingMutex.Lock()
+	pendingSet = make(map[string]*pendingSubscription)
+	pendingMutex.Unlock()
+}
+
+func newConfirmRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	s, err := store.Open("file://" + filepath.Join(t.TempDir(), "emails.txt"))
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	emailStore = s
+
+	router := gin.New()
+	router.GET("/coming-soon/confirm", confirmHandler)
+	return router
+}
+
+// TestConfirmHandlerPromotesPendingSubscription covers the happy path: a
+// valid, unexpired token confirms the matching store record.
+func TestConfirmHandlerPromotesPendingSubscription(t *testing.T) {
+	resetPendingSet()
+	router := newConfirmRouter(t)
+
+	if err := emailStore.Add(context.Background(), "a@example.com", "1.1.1.1", "ua"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	pendingMutex.Lock()
+	pendingSet["tok"] = &pendingSubscription{email: "a@example.com", expiresAt: time.Now().Add(time.Hour)}
+	pendingMutex.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/coming-soon/confirm?token=tok", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	records, err := emailStore.List(context.Background(), false)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(records) != 1 || records[0].ConfirmedAt == nil {
+		t.Fatalf("want the record confirmed, got %+v", records)
+	}
+
+	pendingMutex.Lock()
+	_, stillPending := pendingSet["tok"]
+	pendingMutex.Unlock()
+	if stillPending {
+		t.Error("confirmHandler must consume the token so it can't be replayed")
+	}
+}
+
+// TestConfirmHandlerRejectsExpiredToken covers the race this endpoint is
+// meant to resolve against expirePendingLoop: a token past its expiresAt
+// must be rejected even if the sweep hasn't run yet.
+func TestConfirmHandlerRejectsExpiredToken(t *testing.T) {
+	resetPendingSet()
+	router := newConfirmRouter(t)
+
+	pendingMutex.Lock()
+	pendingSet["expired"] = &pendingSubscription{email: "b@example.com", expiresAt: time.Now().Add(-time.Minute)}
+	pendingMutex.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/coming-soon/confirm?token=expired", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("want status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	pendingMutex.Lock()
+	_, stillPending := pendingSet["expired"]
+	pendingMutex.Unlock()
+	if stillPending {
+		t.Error("an expired token must be evicted on use, not left for expirePendingLoop")
+	}
+}
+
+// TestConfirmHandlerRejectsUnknownToken covers a token that was never
+// issued.
+func TestConfirmHandlerRejectsUnknownToken(t *testing.T) {
+	resetPendingSet()
+	router := newConfirmRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/coming-soon/confirm?token=never-issued", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("want status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+// TestExpirePendingSweepRemovesOnlyExpired mirrors the sweep body of
+// expirePendingLoop without waiting on its 10-minute ticker.
+func TestExpirePendingSweepRemovesOnlyExpired(t *testing.T) {
+	resetPendingSet()
+
+	pendingMutex.Lock()
+	pendingSet["stale"] = &pendingSubscription{email: "stale@example.com", expiresAt: time.Now().Add(-time.Hour)}
+	pendingMutex.Unlock()
+	pendingMutex.Lock()
+	pendingSet["fresh"] = &pendingSubscription{email: "fresh@example.com", expiresAt: time.Now().Add(time.Hour)}
+	pendingMutex.Unlock()
+
+	now := time.Now()
+	pendingMutex.Lock()
+	for token, pending := range pendingSet {
+		if now.After(pending.expiresAt) {
+			delete(pendingSet, token)
+		}
+	}
+	pendingMutex.Unlock()
+
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+	if _, ok := pendingSet["stale"]; ok {
+		t.Error("want the expired entry swept")
+	}
+	if _, ok := pendingSet["fresh"]; !ok {
+		t.Error("want the unexpired entry kept")
+	}
+}
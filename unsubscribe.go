@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// generateUnsubscribeToken signs email together with the current time using
+// HMAC-SHA256 over SECRET_TOKEN, and base64url-encodes the result so it can
+// be embedded in a mailto-safe URL. Tokens don't expire: an unsubscribe link
+// must keep working for as long as the confirmation email sits unread.
+func generateUnsubscribeToken(email string) string {
+	issuedAt := time.Now().Unix()
+	payload := fmt.Sprintf("%s|%d", email, issuedAt)
+	sig := signUnsubscribePayload(payload)
+	return base64.URLEncoding.EncodeToString([]byte(payload + "|" + sig))
+}
+
+// verifyUnsubscribeToken decodes and checks a token produced by
+// generateUnsubscribeToken, returning the email it was issued for.
+func verifyUnsubscribeToken(token string) (string, bool) {
+	raw, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return "", false
+	}
+
+	// Split from the right: issued_at and the signature never contain "|",
+	// but an email's local part may.
+	s := string(raw)
+	sigAt := strings.LastIndex(s, "|")
+	if sigAt < 0 {
+		return "", false
+	}
+	payload, sig := s[:sigAt], s[sigAt+1:]
+	issuedAt := strings.LastIndex(payload, "|")
+	if issuedAt < 0 {
+		return "", false
+	}
+	email := payload[:issuedAt]
+
+	expected := signUnsubscribePayload(payload)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return "", false
+	}
+	return email, true
+}
+
+func signUnsubscribePayload(payload string) string {
+	mac := hmac.New(sha256.New, []byte(secretToken))
+	mac.Write([]byte(payload))
+	return base64.URLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// unsubscribeURL builds the one-click unsubscribe link for email, suitable
+// for both the List-Unsubscribe header and the link in the mail body.
+func unsubscribeURL(email string) string {
+	return fmt.Sprintf("%s/coming-soon/unsubscribe?token=%s", host, generateUnsubscribeToken(email))
+}
+
+// unsubscribeHeaders returns the RFC 8058 List-Unsubscribe headers for an
+// outbound email to the given address.
+func unsubscribeHeaders(email string) map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      fmt.Sprintf("<%s>", unsubscribeURL(email)),
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// unsubscribeHandler marks the email carried by the token as unsubscribed.
+// It backs both the link a user clicks (GET) and the RFC 8058 one-click
+// POST mail clients send automatically, and requires no authentication.
+func unsubscribeHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'token' query parameter."})
+		return
+	}
+
+	email, ok := verifyUnsubscribeToken(token)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unsubscribe token."})
+		return
+	}
+
+	if err := emailStore.Unsubscribe(c.Request.Context(), email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not process unsubscribe request."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "You have been unsubscribed."})
+}
+
+// parseIncludeUnsubscribed reads the ?include_unsubscribed=true query flag
+// used by getEmailsHandler.
+func parseIncludeUnsubscribed(c *gin.Context) bool {
+	include, _ := strconv.ParseBool(c.Query("include_unsubscribed"))
+	return include
+}
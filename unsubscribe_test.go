@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Nawy/coming-soon-service/store"
+)
+
+// TestUnsubscribeTokenRoundTrip covers generate/verify agreeing on the
+// signed email, and that the link carries RFC 8058's required headers.
+func TestUnsubscribeTokenRoundTrip(t *testing.T) {
+	secretToken = "test-secret"
+	host = "https://example.com"
+
+	token := generateUnsubscribeToken("a@example.com")
+	email, ok := verifyUnsubscribeToken(token)
+	if !ok || email != "a@example.com" {
+		t.Fatalf("want (a@example.com, true), got (%q, %v)", email, ok)
+	}
+
+	headers := unsubscribeHeaders("a@example.com")
+	if !strings.HasPrefix(headers["List-Unsubscribe"], "<https://example.com/coming-soon/unsubscribe?token=") {
+		t.Errorf("unexpected List-Unsubscribe header: %q", headers["List-Unsubscribe"])
+	}
+	if headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Errorf("unexpected List-Unsubscribe-Post header: %q", headers["List-Unsubscribe-Post"])
+	}
+}
+
+// TestUnsubscribeTokenRejectsTampering covers the HMAC signature check: a
+// valid token with extra bytes appended, and a token checked against a
+// different secret, must fail verification.
+func TestUnsubscribeTokenRejectsTampering(t *testing.T) {
+	secretToken = "test-secret"
+
+	token := generateUnsubscribeToken("a@example.com")
+
+	if _, ok := verifyUnsubscribeToken(token + "x"); ok {
+		t.Error("want tampered token to fail verification")
+	}
+
+	forged := generateUnsubscribeToken("attacker@example.com")
+	if email, ok := verifyUnsubscribeToken(forged); !ok || email != "attacker@example.com" {
+		t.Fatalf("sanity check failed: a token signed with the real secret must itself verify")
+	}
+
+	// A token signed with a different secret must not verify against the
+	// configured one, even for the same email.
+	secretToken = "a-different-secret"
+	if _, ok := verifyUnsubscribeToken(token); ok {
+		t.Error("want a token signed under a different secret to fail verification")
+	}
+}
+
+// TestUnsubscribeTokenRejectsGarbage covers malformed input that isn't even
+// shaped like a token.
+func TestUnsubscribeTokenRejectsGarbage(t *testing.T) {
+	secretToken = "test-secret"
+
+	for _, bad := range []string{"", "not-base64!!", "YQ=="} {
+		if _, ok := verifyUnsubscribeToken(bad); ok {
+			t.Errorf("want %q to fail verification", bad)
+		}
+	}
+}
+
+// TestUnsubscribeHandlerMarksStoreRecord covers the end-to-end handler: a
+// valid token unsubscribes the matching store record, and a tampered token
+// is rejected with 400.
+func TestUnsubscribeHandlerMarksStoreRecord(t *testing.T) {
+	secretToken = "test-secret"
+
+	s, err := store.Open("file://" + filepath.Join(t.TempDir(), "emails.txt"))
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	emailStore = s
+	if err := emailStore.Add(context.Background(), "a@example.com", "1.1.1.1", "ua"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/coming-soon/unsubscribe", unsubscribeHandler)
+
+	token := generateUnsubscribeToken("a@example.com")
+	req := httptest.NewRequest(http.MethodGet, "/coming-soon/unsubscribe?token="+token, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	records, err := emailStore.List(context.Background(), true)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(records) != 1 || records[0].UnsubscribedAt == nil {
+		t.Fatalf("want the record unsubscribed, got %+v", records)
+	}
+
+	// A tampered token must not be able to unsubscribe anyone.
+	req2 := httptest.NewRequest(http.MethodGet, "/coming-soon/unsubscribe?token="+token+"x", nil)
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+	if w2.Code != http.StatusBadRequest {
+		t.Fatalf("want status %d for a tampered token, got %d", http.StatusBadRequest, w2.Code)
+	}
+}